@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
+)
+
+// assignedDevIDs returns the devIDs on this node whose podMap already
+// contains pod, so AttachGPU can restrict its search to unassigned devices
+// and DetachGPU knows which devices are actually eligible to be freed.
+func (n *NodeInfo) assignedDevIDs(pod *v1.Pod) (devIDs []int) {
+	for id, dev := range n.devs {
+		if _, found := dev.podMap[pod.UID]; found {
+			devIDs = append(devIDs, id)
+		}
+	}
+	return devIDs
+}
+
+// AssignedDevIDs is the exported form of assignedDevIDs, used by the
+// pkg/hotplug gRPC server to report which devices a pod ends up on after an
+// AttachGPU/AttachAll/DetachGPU call.
+func (n *NodeInfo) AssignedDevIDs(pod *v1.Pod) []int {
+	n.rwmu.RLock()
+	defer n.rwmu.RUnlock()
+	return n.assignedDevIDs(pod)
+}
+
+// AttachGPU grows an already-bound pod's GPU allocation by extraCount
+// devices, each reserving extraMem, restricted to devices not already
+// assigned to the pod. It patches the pod annotation with the same
+// optimistic-lock retry loop Allocate uses, then updates DeviceInfo.PodMap
+// for the newly attached devices.
+func (n *NodeInfo) AttachGPU(clientset *kubernetes.Clientset, pod *v1.Pod, extraMem uint, extraCount int) (err error) {
+	n.rwmu.Lock()
+	defer n.rwmu.Unlock()
+
+	assigned := map[int]bool{}
+	for _, id := range n.assignedDevIDs(pod) {
+		assigned[id] = true
+	}
+
+	computePolicy := utils.GetComputePolicy(pod)
+	fitting := map[int]uint{}
+	for id, capacity := range n.getAvailableGPUs(computePolicy) {
+		if assigned[id] {
+			continue
+		}
+		if capacity >= extraMem {
+			fitting[id] = capacity
+		}
+	}
+
+	if len(fitting) < extraCount {
+		return fmt.Errorf("AttachGPU: node %s has only %d free devices with %d mem for pod %s in ns %s, need %d",
+			n.name, len(fitting), extraMem, pod.Name, pod.Namespace, extraCount)
+	}
+
+	policy := utils.GetSchedulePolicy(pod, n.defaultSchedulePolicy)
+	chosen := selectDevicesByPolicy(fitting, extraCount, policy, n.nvlinkMatrix)
+
+	// PatchPodAnnotationSpec rewrites the GPU-ID annotation from the map it's
+	// given rather than merging into the pod's existing one (the same
+	// all-devices-flagged shape Allocate passes it). Build the full set of
+	// devices the pod should end up on - already-assigned plus newly
+	// chosen - so the patch appends instead of clobbering the pod's
+	// original devices.
+	devIDs := map[int]int{}
+	for id := range n.devs {
+		devIDs[id] = 0
+	}
+	for id := range assigned {
+		devIDs[id] = 1
+	}
+	for _, id := range chosen {
+		devIDs[id] = 1
+	}
+
+	newPod, err := n.patchDevIDsAnnotation(clientset, pod, devIDs, extraMem)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range chosen {
+		n.devs[id].addPod(newPod)
+	}
+
+	log.Printf("info: AttachGPU() attached devs %v to pod %s in ns %s on node %s", chosen, pod.Name, pod.Namespace, n.name)
+	return nil
+}
+
+// AttachAll grabs every currently-free device on the node for pod in one
+// shot, useful for elastic training jobs that expand between epochs.
+func (n *NodeInfo) AttachAll(clientset *kubernetes.Clientset, pod *v1.Pod, extraMem uint) (err error) {
+	n.rwmu.RLock()
+	computePolicy := utils.GetComputePolicy(pod)
+	assigned := map[int]bool{}
+	for _, id := range n.assignedDevIDs(pod) {
+		assigned[id] = true
+	}
+	free := 0
+	for id, capacity := range n.getAvailableGPUs(computePolicy) {
+		if !assigned[id] && capacity >= extraMem {
+			free++
+		}
+	}
+	n.rwmu.RUnlock()
+
+	if free == 0 {
+		return fmt.Errorf("AttachAll: node %s has no free devices with %d mem for pod %s in ns %s", n.name, extraMem, pod.Name, pod.Namespace)
+	}
+	return n.AttachGPU(clientset, pod, extraMem, free)
+}
+
+// DetachGPU frees the devices in devIDs that are currently assigned to pod,
+// symmetrically with AttachGPU: it patches the pod's GPU-ID annotation down
+// to the remaining devices before updating DeviceInfo.PodMap, so neither the
+// device plugin nor a cache rebuild from annotations (addOrUpdatePod) keeps
+// enforcing or re-adding the freed devices. Devices not assigned to pod are
+// ignored.
+func (n *NodeInfo) DetachGPU(clientset *kubernetes.Clientset, pod *v1.Pod, devIDs []int) (err error) {
+	n.rwmu.Lock()
+	defer n.rwmu.Unlock()
+
+	toDetach := map[int]bool{}
+	for _, id := range devIDs {
+		dev, found := n.devs[id]
+		if !found {
+			log.Printf("warn: DetachGPU: node %s has no device %d", n.name, id)
+			continue
+		}
+		if _, assigned := dev.podMap[pod.UID]; !assigned {
+			log.Printf("warn: DetachGPU: pod %s in ns %s is not assigned to dev %d on node %s", pod.Name, pod.Namespace, id, n.name)
+			continue
+		}
+		toDetach[id] = true
+	}
+
+	remaining := map[int]int{}
+	for id := range n.devs {
+		remaining[id] = 0
+	}
+	for _, id := range n.assignedDevIDs(pod) {
+		if !toDetach[id] {
+			remaining[id] = 1
+		}
+	}
+
+	perDeviceMem := uint(utils.GetGPUMemoryFromPodAnnotation(pod))
+	newPod, err := n.patchDevIDsAnnotation(clientset, pod, remaining, perDeviceMem)
+	if err != nil {
+		return err
+	}
+
+	for id := range toDetach {
+		n.devs[id].removePod(newPod)
+	}
+
+	log.Printf("info: DetachGPU() detached devs %v from pod %s in ns %s on node %s", devIDs, pod.Name, pod.Namespace, n.name)
+	return nil
+}
+
+// patchDevIDsAnnotation patches pod's annotations so its GPU IDs become the
+// devices flagged in devIDs, at perDeviceMem, retrying once on the same optimistic-lock conflict Allocate
+// handles.
+func (n *NodeInfo) patchDevIDsAnnotation(clientset *kubernetes.Clientset, pod *v1.Pod, devIDs map[int]int, perDeviceMem uint) (*v1.Pod, error) {
+	patchedAnnotationBytes, err := utils.PatchPodAnnotationSpec(pod, devIDs, int(perDeviceMem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate patched annotations,reason: %v", err)
+	}
+
+	newPod, err := clientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.StrategicMergePatchType, patchedAnnotationBytes)
+	if err != nil {
+		if err.Error() == OptimisticLockErrorMsg {
+			pod, err = clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			newPod, err = clientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.StrategicMergePatchType, patchedAnnotationBytes)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	return newPod, nil
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SchedulerCache holds the per-node NodeInfo the extender's routes look
+// node state up from (see routes.NodeInfoGetter and hotplug.NodeInfoGetter).
+// It's the home for the node-add/update/delete events a node informer feeds
+// it, turning them into NewNodeInfo/Reset calls on the right NodeInfo.
+type SchedulerCache struct {
+	rwmu  sync.RWMutex
+	nodes map[string]*NodeInfo
+}
+
+// NewSchedulerCache returns an empty SchedulerCache.
+func NewSchedulerCache() *SchedulerCache {
+	return &SchedulerCache{nodes: map[string]*NodeInfo{}}
+}
+
+// GetNodeInfo returns the cached NodeInfo for name, if any.
+func (c *SchedulerCache) GetNodeInfo(name string) (*NodeInfo, bool) {
+	c.rwmu.RLock()
+	defer c.rwmu.RUnlock()
+	nodeInfo, found := c.nodes[name]
+	return nodeInfo, found
+}
+
+// AddOrUpdateNode builds or refreshes the NodeInfo for node. On a new node
+// it calls NewNodeInfo and only caches the result if it's non-nil -
+// NewNodeInfo returns nil for nodes NewNodeInfo's strict device-type check
+// rejects (exclusive or unlabelled, once its own legacy-label migration
+// doesn't apply), and those must not be cached. On a node already in the
+// cache it calls Reset to pick up the node's current GPU count/memory.
+// clientset is threaded through to both so their device-type label
+// migration can patch the node.
+func (c *SchedulerCache) AddOrUpdateNode(clientset *kubernetes.Clientset, node *v1.Node) {
+	c.rwmu.Lock()
+	defer c.rwmu.Unlock()
+
+	if nodeInfo, found := c.nodes[node.Name]; found {
+		nodeInfo.Reset(clientset, node)
+		return
+	}
+
+	nodeInfo := NewNodeInfo(clientset, node)
+	if nodeInfo == nil {
+		return
+	}
+	c.nodes[node.Name] = nodeInfo
+}
+
+// RemoveNode drops node from the cache, e.g. on a node delete event.
+func (c *SchedulerCache) RemoveNode(name string) {
+	c.rwmu.Lock()
+	defer c.rwmu.Unlock()
+	delete(c.nodes, name)
+}
@@ -0,0 +1,61 @@
+package collector
+
+import "sync"
+
+// DeviceHealth is the latest observed state of a single GPU as reported by
+// the per-node collector sidecar (backed by NVML, or by NodeGPUStatus for
+// consumers that only have API access to the node).
+type DeviceHealth struct {
+	Healthy       bool
+	SMUtilization uint32 // percent, from nvmlDeviceGetUtilizationRates
+	MemoryUsed    uint64 // bytes, from nvmlDeviceGetMemoryInfo
+	PowerState    uint32 // nvmlPstate_t, from nvmlDeviceGetPowerState; informational only
+}
+
+// DeviceCollector streams per-device health and utilization for the GPUs on
+// a single node. Implementations are expected to keep their view fresh in
+// the background between calls to DeviceHealth; callers should not block on
+// Start().
+type DeviceCollector interface {
+	// Start begins collecting in the background. It returns once the first
+	// sample has been taken, or immediately for collectors with nothing to
+	// poll (e.g. the dummy collector).
+	Start() error
+	// Stop releases any resources held by the collector.
+	Stop()
+	// DeviceHealth returns the last known health for the device at idx.
+	// found is false if the collector has never observed that device.
+	DeviceHealth(idx int) (health DeviceHealth, found bool)
+}
+
+// NewCollector returns the collector implementation compiled into this
+// binary for nodeName. Builds with the "nvml" build tag get the real NVML
+// collector; all other builds fall back to the dummy collector so the
+// scheduler still builds/runs on platforms without NVML.
+func NewCollector(nodeName string) DeviceCollector {
+	return newPlatformCollector(nodeName)
+}
+
+// staticCollector is a small helper embedded by collector implementations
+// that just need a goroutine-safe map of the last observed samples.
+type staticCollector struct {
+	mu      sync.RWMutex
+	samples map[int]DeviceHealth
+}
+
+func newStaticCollector() *staticCollector {
+	return &staticCollector{samples: map[int]DeviceHealth{}}
+}
+
+func (c *staticCollector) DeviceHealth(idx int) (DeviceHealth, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	h, found := c.samples[idx]
+	return h, found
+}
+
+func (c *staticCollector) set(idx int, h DeviceHealth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples[idx] = h
+}
@@ -0,0 +1,27 @@
+// +build !nvml
+
+package collector
+
+// dummyCollector is used on platforms without NVML (or binaries built
+// without the "nvml" tag). It reports every device as healthy with no
+// utilization data, mirroring koordlet's dummyDeviceManager so the
+// scheduler still builds/runs where GPUs aren't present or NVML can't be
+// linked.
+type dummyCollector struct {
+	*staticCollector
+}
+
+func newPlatformCollector(nodeName string) DeviceCollector {
+	return &dummyCollector{staticCollector: newStaticCollector()}
+}
+
+func (c *dummyCollector) Start() error {
+	return nil
+}
+
+func (c *dummyCollector) Stop() {
+}
+
+func (c *dummyCollector) DeviceHealth(idx int) (DeviceHealth, bool) {
+	return DeviceHealth{Healthy: true}, false
+}
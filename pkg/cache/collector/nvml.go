@@ -0,0 +1,117 @@
+// +build nvml
+
+package collector
+
+import (
+	"log"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// pollInterval is how often the nvmlCollector refreshes ECC/XID health and
+// utilization for every device on the node.
+const pollInterval = 5 * time.Second
+
+// nvmlCollector enumerates devices on the local node via NVML and keeps
+// their health/utilization fresh in the background. It is meant to run
+// inside the collector DaemonSet sidecar, one instance per node.
+type nvmlCollector struct {
+	*staticCollector
+	nodeName string
+	stopCh   chan struct{}
+}
+
+func newPlatformCollector(nodeName string) DeviceCollector {
+	return &nvmlCollector{
+		staticCollector: newStaticCollector(),
+		nodeName:        nodeName,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (c *nvmlCollector) Start() error {
+	if ret := nvml.Init(); ret != nvml.SUCCESS {
+		return nvmlErr("nvml.Init", ret)
+	}
+
+	c.poll()
+	go c.loop()
+	return nil
+}
+
+func (c *nvmlCollector) Stop() {
+	close(c.stopCh)
+	nvml.Shutdown()
+}
+
+func (c *nvmlCollector) loop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.poll()
+		}
+	}
+}
+
+// poll refreshes health, SM utilization and memory-used for every device
+// visible to NVML on this node.
+func (c *nvmlCollector) poll() {
+	count, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		log.Printf("warn: nvmlCollector: failed to get device count on node %s: %v", c.nodeName, ret)
+		return
+	}
+
+	for idx := 0; idx < count; idx++ {
+		dev, ret := nvml.DeviceGetHandleByIndex(idx)
+		if ret != nvml.SUCCESS {
+			log.Printf("warn: nvmlCollector: failed to get device %d on node %s: %v", idx, c.nodeName, ret)
+			continue
+		}
+		c.set(idx, sampleDevice(dev))
+	}
+}
+
+func sampleDevice(dev nvml.Device) DeviceHealth {
+	health := DeviceHealth{Healthy: true}
+
+	if xidCount, ret := dev.GetXidErrors(); ret == nvml.SUCCESS && xidCount > 0 {
+		// a non-zero XID error count means the device just faulted.
+		health.Healthy = false
+	}
+	if eccCount, ret := dev.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC); ret == nvml.SUCCESS && eccCount > 0 {
+		// uncorrected ECC errors since the last driver load.
+		health.Healthy = false
+	}
+	if state, ret := dev.GetPowerState(); ret == nvml.SUCCESS {
+		// informational only - surfaced on DeviceHealth, doesn't affect Healthy.
+		health.PowerState = uint32(state)
+	}
+
+	if rates, ret := dev.GetUtilizationRates(); ret == nvml.SUCCESS {
+		health.SMUtilization = rates.Gpu
+	}
+	if mem, ret := dev.GetMemoryInfo(); ret == nvml.SUCCESS {
+		health.MemoryUsed = mem.Used
+	}
+
+	return health
+}
+
+func nvmlErr(op string, ret nvml.Return) error {
+	return &nvmlError{op: op, ret: ret}
+}
+
+type nvmlError struct {
+	op  string
+	ret nvml.Return
+}
+
+func (e *nvmlError) Error() string {
+	return e.op + ": " + nvml.ErrorString(e.ret)
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
+)
+
+// effectiveComputePolicyPatch builds the JSON merge patch that records
+// policy under utils.EffectiveComputePolicyAnnotation on a pod.
+func effectiveComputePolicyPatch(policy utils.ComputePolicy) []byte {
+	return []byte(fmt.Sprintf(
+		`{"metadata":{"annotations":{%q:%q}}}`,
+		utils.EffectiveComputePolicyAnnotation,
+		string(policy),
+	))
+}
+
+// BurstShareOvercommitFactor is the multiplier applied to a device's
+// physical memory capacity when computing availability for pods that opt
+// into utils.ComputePolicyBurstShare. It is a package-level default,
+// overridable with SetBurstShareOvercommitFactor, mirroring how the
+// scheduler's other defaults (e.g. default SchedulePolicy) are configured.
+var BurstShareOvercommitFactor = 1.5
+
+// SetBurstShareOvercommitFactor overrides the default burst-share overcommit
+// factor, e.g. from a scheduler command-line flag.
+func SetBurstShareOvercommitFactor(factor float64) {
+	BurstShareOvercommitFactor = factor
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"log"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
+)
+
+// DeviceInfo is device level aggregated information.
+type DeviceInfo struct {
+	idx         int
+	totalGPUMem uint
+	podMap      map[types.UID]*v1.Pod
+	rwmu        *sync.RWMutex
+	// fixedShareCount and burstShareCount track how many pods on this
+	// device were admitted under each ALIYUN_COM_GPU_COMPUTE_POLICY value.
+	// A device with any fixed-share pod refuses to be overcommitted, even
+	// for pods that themselves request burst-share.
+	fixedShareCount int
+	burstShareCount int
+}
+
+func newDeviceInfo(index int, totalGPUMem uint) *DeviceInfo {
+	return &DeviceInfo{
+		idx:         index,
+		totalGPUMem: totalGPUMem,
+		podMap:      map[types.UID]*v1.Pod{},
+		rwmu:        new(sync.RWMutex),
+	}
+}
+
+func (d *DeviceInfo) GetTotalGPUMemory() uint {
+	return d.totalGPUMem
+}
+
+func (d *DeviceInfo) GetIdx() int {
+	return d.idx
+}
+
+// CanOvercommit reports whether this device is free of fixed-share pods and
+// therefore eligible to serve burst-share pods above physical capacity. The
+// other direction of the invariant - a fixed-share pod must not land on a
+// device burst-share pods have already pushed past physical capacity - is
+// enforced in NodeInfo.getAvailableGPUs, which clamps against EffectiveCapacity
+// rather than here, since it needs the live-collector-adjusted usage total.
+func (d *DeviceInfo) CanOvercommit() bool {
+	d.rwmu.RLock()
+	defer d.rwmu.RUnlock()
+	return d.fixedShareCount == 0
+}
+
+// EffectiveCapacity returns the memory capacity this device should be
+// considered to have for a pod requesting policy. Fixed-share pods always
+// see the physical capacity; burst-share pods see the overcommitted
+// capacity, unless the device already hosts a fixed-share pod.
+func (d *DeviceInfo) EffectiveCapacity(policy utils.ComputePolicy) uint {
+	if policy == utils.ComputePolicyBurstShare && d.CanOvercommit() {
+		return uint(float64(d.totalGPUMem) * BurstShareOvercommitFactor)
+	}
+	return d.totalGPUMem
+}
+
+func (d *DeviceInfo) GetUsedGPUMemory() (used uint) {
+	d.rwmu.RLock()
+	defer d.rwmu.RUnlock()
+	for _, pod := range d.podMap {
+		used += uint(utils.GetGPUMemoryFromPodAnnotation(pod))
+	}
+	return used
+}
+
+func (d *DeviceInfo) addPod(pod *v1.Pod) {
+	d.rwmu.Lock()
+	defer d.rwmu.Unlock()
+	log.Printf("debug: dev.addPod() Pod %s in ns %s with the GPU ID %d added to device map",
+		pod.Name, pod.Namespace, d.idx)
+	if old, found := d.podMap[pod.UID]; found {
+		if utils.GetComputePolicy(old) == utils.ComputePolicyBurstShare {
+			d.burstShareCount--
+		} else {
+			d.fixedShareCount--
+		}
+	}
+	d.podMap[pod.UID] = pod
+	if utils.GetComputePolicy(pod) == utils.ComputePolicyBurstShare {
+		d.burstShareCount++
+	} else {
+		d.fixedShareCount++
+	}
+}
+
+func (d *DeviceInfo) removePod(pod *v1.Pod) {
+	d.rwmu.Lock()
+	defer d.rwmu.Unlock()
+	log.Printf("debug: dev.removePod() Pod %s in ns %s with the GPU ID %d removed from device map",
+		pod.Name, pod.Namespace, d.idx)
+	if _, found := d.podMap[pod.UID]; found {
+		if utils.GetComputePolicy(pod) == utils.ComputePolicyBurstShare {
+			d.burstShareCount--
+		} else {
+			d.fixedShareCount--
+		}
+	}
+	delete(d.podMap, pod.UID)
+}
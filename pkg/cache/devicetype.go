@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DeviceTypeLabel marks whether a node should be managed by this extender
+// at all. In strict mode (StrictDeviceType), nodes labelled "exclusive" or
+// missing the label are left alone so the extender doesn't act on plain nvidia.com/gpu nodes
+// and doesn't log noisy "no devices" warnings for them.
+const DeviceTypeLabel = "gpushare.alibabacloud.com/device-type"
+
+const (
+	DeviceTypeShare     = "share"
+	DeviceTypeExclusive = "exclusive"
+)
+
+// legacyGPUCountLabel is the older label used before DeviceTypeLabel
+// existed; Reset uses its presence to auto-migrate a node to "share".
+const legacyGPUCountLabel = "aliyun.com/gpu-count"
+
+// StrictDeviceType controls whether NewNodeInfo refuses to cache nodes that
+// isShareNode doesn't recognize as share nodes. Off by default so upgrades
+// don't suddenly stop scheduling on nodes that haven't been labelled yet;
+// turn it on once the device-type label has been rolled out to the fleet.
+var StrictDeviceType = false
+
+// SetStrictDeviceType enables/disables StrictDeviceType, e.g. from a
+// scheduler command-line flag.
+func SetStrictDeviceType(strict bool) {
+	StrictDeviceType = strict
+}
+
+// isShareNode reports whether node should be treated as a GPU-sharing node:
+// either explicitly labelled DeviceTypeShare, or carrying the legacy
+// legacyGPUCountLabel with no explicit DeviceTypeLabel. The legacy case
+// matters in strict mode: a node that only has the legacy label must still
+// be recognized as a share node on its very first NewNodeInfo call, before
+// migrateDeviceTypeLabel's patch has had a chance to land and be re-read.
+func isShareNode(node *v1.Node) bool {
+	if deviceType, hasDeviceType := node.Labels[DeviceTypeLabel]; hasDeviceType {
+		return deviceType == DeviceTypeShare
+	}
+	_, hasLegacyLabel := node.Labels[legacyGPUCountLabel]
+	return hasLegacyLabel
+}
+
+// migrateDeviceTypeLabel applies DeviceTypeLabel=share to node via
+// clientset if the label is missing but the older legacyGPUCountLabel is
+// present, so nodes set up before this label existed keep being scheduled
+// onto without an operator manually labelling every node.
+func migrateDeviceTypeLabel(clientset *kubernetes.Clientset, node *v1.Node) {
+	if clientset == nil {
+		return
+	}
+	if _, hasDeviceType := node.Labels[DeviceTypeLabel]; hasDeviceType {
+		return
+	}
+	if _, hasLegacyLabel := node.Labels[legacyGPUCountLabel]; !hasLegacyLabel {
+		return
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, DeviceTypeLabel, DeviceTypeShare))
+	if _, err := clientset.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, patch); err != nil {
+		log.Printf("warn: migrateDeviceTypeLabel: failed to label node %s as %s=%s: %v", node.Name, DeviceTypeLabel, DeviceTypeShare, err)
+		return
+	}
+	log.Printf("info: migrateDeviceTypeLabel: node %s had no %s label but has %s; labelled it %s", node.Name, DeviceTypeLabel, legacyGPUCountLabel, DeviceTypeShare)
+}
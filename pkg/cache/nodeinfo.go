@@ -3,14 +3,13 @@ package cache
 import (
 	"fmt"
 	"log"
-	"strconv"
-	"strings"
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
 
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/cache/collector"
 	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -20,6 +19,11 @@ const (
 	OptimisticLockErrorMsg = "the object has been modified; please apply your changes to the latest version and try again"
 )
 
+// bytesPerMiB converts a collector-reported memory-used value (bytes, as
+// returned by nvmlDeviceGetMemoryInfo) into the MiB unit gpuTotalMemory and
+// the aliyun.com/gpu-mem resource are expressed in.
+const bytesPerMiB = 1024 * 1024
+
 // NodeInfo is node level aggregated information.
 type NodeInfo struct {
 	name           string
@@ -28,12 +32,42 @@ type NodeInfo struct {
 	gpuCount       int
 	gpuTotalMemory int
 	rwmu           *sync.RWMutex
+	// collector streams live per-GPU health and utilization for this node,
+	// populated by the collector DaemonSet sidecar via NVML (or the CRD it
+	// publishes to). It replaces the old unhealthy-gpu-<node> ConfigMap.
+	collector collector.DeviceCollector
+	// nvlinkMatrix is the NxN link weight matrix for this node, published
+	// in the NodeGPUStatus CRD; nil until SetNVLinkMatrix is called.
+	nvlinkMatrix [][]int
+	// defaultSchedulePolicy is used for pods that don't set the
+	// ALIYUN_COM_GPU_SCHEDULE_POLICY annotation.
+	defaultSchedulePolicy utils.SchedulePolicy
+}
+
+// SetDefaultSchedulePolicy sets the scheduler-wide default SchedulePolicy
+// applied to pods that don't request one explicitly via annotation.
+func (n *NodeInfo) SetDefaultSchedulePolicy(policy utils.SchedulePolicy) {
+	n.rwmu.Lock()
+	defer n.rwmu.Unlock()
+	n.defaultSchedulePolicy = policy
 }
 
 // Create Node Level
-func NewNodeInfo(node *v1.Node) *NodeInfo {
+func NewNodeInfo(clientset *kubernetes.Clientset, node *v1.Node) *NodeInfo {
 	log.Printf("debug: NewNodeInfo() creates nodeInfo for %s", node.Name)
 
+	// Run before the strict-mode rejection below: a node seen for the
+	// first time here never goes through Reset, so this is the only
+	// chance to auto-label a legacy (aliyun.com/gpu-count-only) node
+	// DeviceTypeShare before it would otherwise be dropped uncached.
+	migrateDeviceTypeLabel(clientset, node)
+
+	if StrictDeviceType && !isShareNode(node) {
+		log.Printf("info: NewNodeInfo() skip node %s: %s=%q, want %q",
+			node.Name, DeviceTypeLabel, node.Labels[DeviceTypeLabel], DeviceTypeShare)
+		return nil
+	}
+
 	devMap := map[int]*DeviceInfo{}
 	for i := 0; i < utils.GetGPUCountInNode(node); i++ {
 		devMap[i] = newDeviceInfo(i, uint(utils.GetTotalGPUMemory(node)/utils.GetGPUCountInNode(node)))
@@ -45,18 +79,35 @@ func NewNodeInfo(node *v1.Node) *NodeInfo {
 			node)
 	}
 
+	devCollector := collector.NewCollector(node.Name)
+	if err := devCollector.Start(); err != nil {
+		log.Printf("warn: NewNodeInfo() failed to start device collector for %s: %v", node.Name, err)
+	}
+
 	return &NodeInfo{
-		name:           node.Name,
-		node:           node,
-		devs:           devMap,
-		gpuCount:       utils.GetGPUCountInNode(node),
-		gpuTotalMemory: utils.GetTotalGPUMemory(node),
-		rwmu:           new(sync.RWMutex),
+		name:                  node.Name,
+		node:                  node,
+		devs:                  devMap,
+		gpuCount:              utils.GetGPUCountInNode(node),
+		gpuTotalMemory:        utils.GetTotalGPUMemory(node),
+		rwmu:                  new(sync.RWMutex),
+		collector:             devCollector,
+		defaultSchedulePolicy: utils.SchedulePolicyBinpack,
 	}
 }
 
+// GetLiveUtilization returns the most recently observed SM utilization
+// percentage and memory used, in bytes, for the device at devID as reported
+// by the node's collector. found is false if no sample has been taken yet.
+func (n *NodeInfo) GetLiveUtilization(devID int) (smPct uint32, memUsed uint64, found bool) {
+	health, found := n.collector.DeviceHealth(devID)
+	return health.SMUtilization, health.MemoryUsed, found
+}
+
 // Only update the devices when the length of devs is 0
-func (n *NodeInfo) Reset(node *v1.Node) {
+func (n *NodeInfo) Reset(clientset *kubernetes.Clientset, node *v1.Node) {
+	migrateDeviceTypeLabel(clientset, node)
+
 	n.gpuCount = utils.GetGPUCountInNode(node)
 	n.gpuTotalMemory = utils.GetTotalGPUMemory(node)
 	n.node = node
@@ -150,7 +201,7 @@ func (n *NodeInfo) Assume(pod *v1.Pod) (allocatable bool) {
 	n.rwmu.RLock()
 	defer n.rwmu.RUnlock()
 
-	availableGPUs := n.getAvailableGPUs()
+	availableGPUs := n.getAvailableGPUs(utils.GetComputePolicy(pod))
 	reqGPUMem := uint(utils.GetGPUMemoryFromPodResource(pod))
 	reqGPUCount := uint(utils.GetGPUCountFromPodResource(pod))
 
@@ -221,6 +272,18 @@ func (n *NodeInfo) Allocate(clientset *kubernetes.Clientset, pod *v1.Pod) (err e
 		err = fmt.Errorf("The node %s can't place the pod %s in ns %s,and the pod spec is %v", pod.Spec.NodeName, pod.Name, pod.Namespace, pod)
 	}
 
+	// 1b. Record the effective compute policy so downstream device plugins
+	// (e.g. cGPU) enforce the matching runtime memory limit.
+	if err == nil {
+		computePolicy := utils.GetComputePolicy(pod)
+		log.Printf("info: Allocate() 1b. Patch effective compute policy %s to pod %s in ns %s.----", computePolicy, pod.Name, pod.Namespace)
+		newPod, err = clientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.MergePatchType, effectiveComputePolicyPatch(computePolicy))
+		if err != nil {
+			log.Printf("warn: failed to patch effective compute policy for pod %s in ns %s: %v", pod.Name, pod.Namespace, err)
+			return err
+		}
+	}
+
 	// 2. Bind the pod to the node
 	if err == nil {
 		binding := &v1.Binding{
@@ -261,62 +324,15 @@ func (n *NodeInfo) Allocate(clientset *kubernetes.Clientset, pod *v1.Pod) (err e
 	return err
 }
 
-// allocate the GPU ID to the pod
-func (n *NodeInfo) allocateGPUID(pod *v1.Pod) (candidateDevID int, found bool) {
-
-	reqGPU := uint(0)
-	found = false
-	candidateDevID = -1
-	candidateGPUMemory := uint(0)
-	availableGPUs := n.getAvailableGPUs()
-
-	reqGPU = uint(utils.GetGPUMemoryFromPodResource(pod))
-
-	if reqGPU > uint(0) {
-		log.Printf("info: reqGPU for pod %s in ns %s: %d", pod.Name, pod.Namespace, reqGPU)
-		log.Printf("info: AvailableGPUs: %v in node %s", availableGPUs, n.name)
-		if len(availableGPUs) > 0 {
-			for devID := 0; devID < len(n.devs); devID++ {
-				availableGPU, ok := availableGPUs[devID]
-				if ok {
-					if availableGPU >= reqGPU {
-						if candidateDevID == -1 || candidateGPUMemory > availableGPU {
-							candidateDevID = devID
-							candidateGPUMemory = availableGPU
-						}
-
-						found = true
-					}
-				}
-			}
-		}
-
-		if found {
-			log.Printf("info: Find candidate dev id %d for pod %s in ns %s successfully.",
-				candidateDevID,
-				pod.Name,
-				pod.Namespace)
-		} else {
-			log.Printf("warn: Failed to find available GPUs %d for the pod %s in the namespace %s",
-				reqGPU,
-				pod.Name,
-				pod.Namespace)
-		}
-	}
-
-	return candidateDevID, found
-}
-
-
 // allocate the GPUs' ID to the pod
 func (n *NodeInfo) allocateGPUIDs(pod *v1.Pod) (candidateDevIDs map[int]int, found bool) {
 
 	reqGPUMem := uint(0)
 	reqGPUCount := 0
 	found = false
-	foundGPUCount := 0
 	candidateDevIDs = map[int]int{}
-	availableGPUs := n.getAvailableGPUs()
+	computePolicy := utils.GetComputePolicy(pod)
+	availableGPUs := n.getAvailableGPUs(computePolicy)
 
 	reqGPUMem = uint(utils.GetGPUMemoryFromPodResource(pod))
 	reqGPUCount = utils.GetGPUCountFromPodResource(pod)
@@ -327,29 +343,36 @@ func (n *NodeInfo) allocateGPUIDs(pod *v1.Pod) (candidateDevIDs map[int]int, fou
 		if reqGPUCount == 0 {
 			reqGPUCount = 1
 		}
-		if len(availableGPUs) > 0 {
-			for devID := 0; devID < len(n.devs); devID++ {
-				availableGPU, ok := availableGPUs[devID]
-				if ok {
-					if availableGPU >= reqGPUMem && foundGPUCount < reqGPUCount{
-						candidateDevIDs[devID] = 1
-						foundGPUCount += 1
-					} else {
-						candidateDevIDs[devID] = 0
-					}
-				}
+
+		fitting := map[int]uint{}
+		for devID, availableGPU := range availableGPUs {
+			if availableGPU >= reqGPUMem {
+				fitting[devID] = availableGPU
 			}
 		}
-		
-		if foundGPUCount == reqGPUCount {
+
+		policy := utils.GetSchedulePolicy(pod, n.defaultSchedulePolicy)
+		if len(fitting) >= reqGPUCount {
+			chosen := selectDevicesByPolicy(fitting, reqGPUCount, policy, n.nvlinkMatrix)
+			for devID := range n.devs {
+				candidateDevIDs[devID] = 0
+			}
+			for _, devID := range chosen {
+				candidateDevIDs[devID] = 1
+			}
 			found = true
+		} else {
+			for devID := range n.devs {
+				candidateDevIDs[devID] = 0
+			}
 		}
 
 		if found {
-			log.Printf("info: Find candidate dev ids %v for pod %s in ns %s successfully.",
+			log.Printf("info: Find candidate dev ids %v for pod %s in ns %s with policy %s successfully.",
 				candidateDevIDs,
 				pod.Name,
-				pod.Namespace)
+				pod.Namespace,
+				policy)
 		} else {
 			log.Printf("warn: Failed to find available %d GPUs %d mem for the pod %s in the namespace %s",
 				reqGPUCount,
@@ -362,13 +385,29 @@ func (n *NodeInfo) allocateGPUIDs(pod *v1.Pod) (candidateDevIDs map[int]int, fou
 	return candidateDevIDs, found
 }
 
-func (n *NodeInfo) getAvailableGPUs() (availableGPUs map[int]uint) {
-	allGPUs := n.getAllGPUs()
+func (n *NodeInfo) getAvailableGPUs(policy utils.ComputePolicy) (availableGPUs map[int]uint) {
+	allGPUs := n.getAllGPUs(policy)
 	usedGPUs := n.getUsedGPUs()
 	unhealthyGPUs := n.getUnhealthyGPUs()
 	availableGPUs = map[int]uint{}
 	for id, totalGPUMem := range allGPUs {
 		if usedGPUMem, found := usedGPUs[id]; found {
+			if _, liveMemUsed, liveFound := n.GetLiveUtilization(id); liveFound {
+				liveMemUsedMiB := uint(liveMemUsed / bytesPerMiB)
+				if liveMemUsedMiB > usedGPUMem {
+					usedGPUMem = liveMemUsedMiB
+				}
+			}
+			// totalGPUMem here is EffectiveCapacity(policy): physical capacity
+			// for a fixed-share requester, even if burst-share pods already on
+			// this device pushed usedGPUMem (their raw reservation sum, not
+			// overcommit-weighted) past it. Clamping keeps a fixed-share pod
+			// from being admitted onto an already-overcommitted device - the
+			// other half of the invariant CanOvercommit enforces for
+			// burst-share pods - and avoids underflowing the uint subtraction.
+			if usedGPUMem > totalGPUMem {
+				usedGPUMem = totalGPUMem
+			}
 			availableGPUs[id] = totalGPUMem - usedGPUMem
 		}
 	}
@@ -392,40 +431,31 @@ func (n *NodeInfo) getUsedGPUs() (usedGPUs map[int]uint) {
 	return usedGPUs
 }
 
-// device index: gpu memory
-func (n *NodeInfo) getAllGPUs() (allGPUs map[int]uint) {
+// device index: gpu memory, at the capacity effectively available to a pod
+// requesting policy (physical capacity for fixed-share, possibly
+// overcommitted for burst-share).
+func (n *NodeInfo) getAllGPUs(policy utils.ComputePolicy) (allGPUs map[int]uint) {
 	allGPUs = map[int]uint{}
 	for _, dev := range n.devs {
-		allGPUs[dev.idx] = dev.totalGPUMem
+		allGPUs[dev.idx] = dev.EffectiveCapacity(policy)
 	}
 	log.Printf("info: getAllGPUs: %v in node %s, and dev %v", allGPUs, n.name, n.devs)
 	return allGPUs
 }
 
-// getUnhealthyGPUs get the unhealthy GPUs from configmap
+// getUnhealthyGPUs gets the unhealthy GPUs from the node's live collector,
+// which is fed by NVML (XID and uncorrected ECC errors) in the collector
+// DaemonSet sidecar; power state is reported but doesn't mark a device
+// unhealthy. This replaces the old unhealthy-gpu-<node> ConfigMap,
+// which missed transient failures and required operators to edit it by hand.
 func (n *NodeInfo) getUnhealthyGPUs() (unhealthyGPUs map[int]bool) {
 	unhealthyGPUs = map[int]bool{}
-	name := fmt.Sprintf("unhealthy-gpu-%s", n.GetName())
-	log.Printf("info: try to find unhealthy node %s", name)
-	cm := getConfigMap(name)
-	if cm == nil {
-		return
-	}
-
-	if devicesStr, found := cm.Data["gpus"]; found {
-		log.Printf("warn: the unhelathy gpus %s", devicesStr)
-		idsStr := strings.Split(devicesStr, ",")
-		for _, sid := range idsStr {
-			id, err := strconv.Atoi(sid)
-			if err != nil {
-				log.Printf("warn: failed to parse id %s due to %v", sid, err)
-			}
+	for id := range n.devs {
+		health, found := n.collector.DeviceHealth(id)
+		if found && !health.Healthy {
+			log.Printf("warn: dev %d on node %s reported unhealthy by collector", id, n.name)
 			unhealthyGPUs[id] = true
 		}
-	} else {
-		log.Println("info: skip, because there are no unhealthy gpus")
 	}
-
 	return
-
 }
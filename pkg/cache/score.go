@@ -0,0 +1,73 @@
+package cache
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
+)
+
+// Score policies for the /gpushare-scheduler/prioritize extender endpoint.
+// They complement the filter-only Assume by letting the scheduler rank
+// nodes that already passed the predicate.
+const (
+	ScorePolicyBinpack           = "binpack"
+	ScorePolicySpread            = "spread"
+	ScorePolicyMostFreeSingleGPU = "most-free-single-gpu"
+)
+
+// maxScore is the ceiling of the extender's 0-100 priority range.
+const maxScore = 100
+
+// Score rates how well-suited this node is for pod under policy, on a 0-100
+// scale, for use by the /gpushare-scheduler/prioritize extender endpoint.
+// Unlike Assume, Score never rejects a node - it's only called for nodes
+// that already passed the filter.
+func (n *NodeInfo) Score(pod *v1.Pod, policy string) int64 {
+	n.rwmu.RLock()
+	defer n.rwmu.RUnlock()
+
+	if n.gpuCount == 0 || n.gpuTotalMemory == 0 {
+		return 0
+	}
+
+	computePolicy := utils.GetComputePolicy(pod)
+	allGPUs := n.getAllGPUs(computePolicy)
+	usedGPUs := n.getUsedGPUs()
+
+	var totalCapacity, totalUsed uint
+	maxFree := uint(0)
+	perDevCapacity := uint(0)
+	for id, capacity := range allGPUs {
+		totalCapacity += capacity
+		used := usedGPUs[id]
+		if used > capacity {
+			used = capacity
+		}
+		totalUsed += used
+		if capacity > used && capacity-used > maxFree {
+			maxFree = capacity - used
+		}
+		if capacity > perDevCapacity {
+			perDevCapacity = capacity
+		}
+	}
+
+	if totalCapacity == 0 {
+		return 0
+	}
+
+	switch policy {
+	case ScorePolicySpread:
+		// Inverse of binpack: favor the least allocated node.
+		return maxScore - int64(float64(totalUsed)/float64(totalCapacity)*maxScore)
+	case ScorePolicyMostFreeSingleGPU:
+		if perDevCapacity == 0 {
+			return 0
+		}
+		return int64(float64(maxFree) / float64(perDevCapacity) * maxScore)
+	case ScorePolicyBinpack:
+		fallthrough
+	default:
+		return int64(float64(totalUsed) / float64(totalCapacity) * maxScore)
+	}
+}
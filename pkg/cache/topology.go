@@ -0,0 +1,168 @@
+package cache
+
+import "github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/utils"
+
+// Link weights used when the per-node NVLink adjacency matrix falls back to
+// PCIe common-ancestor level (from nvmlDeviceGetTopologyCommonAncestor).
+// Higher is better/closer.
+const (
+	LinkWeightNVLink = 4
+	LinkWeightPIX    = 3
+	LinkWeightPXB    = 2
+	LinkWeightPHB    = 1
+	LinkWeightSYS    = 0
+)
+
+// linkAffinityGreedyThreshold is the subset size above which exact
+// enumeration is replaced by a greedy expansion from the highest-weighted
+// seed edge, to keep selection time bounded.
+const linkAffinityGreedyThreshold = 8
+
+// SetNVLinkMatrix installs the per-node NxN link weight matrix (indexed by
+// device id) published in the NodeGPUStatus CRD, used by the link-affinity
+// schedule policy. A nil matrix disables link-affinity scoring and falls
+// back to binpack ordering.
+func (n *NodeInfo) SetNVLinkMatrix(matrix [][]int) {
+	n.rwmu.Lock()
+	defer n.rwmu.Unlock()
+	n.nvlinkMatrix = matrix
+}
+
+// sortByFreeMemory returns devIDs (restricted to those in candidates) sorted
+// ascending (binpack) or descending (spread) by free memory.
+func sortByFreeMemory(candidates map[int]uint, descending bool) []int {
+	ids := make([]int, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+
+	for i := 1; i < len(ids); i++ {
+		for j := i; j > 0; j-- {
+			swap := candidates[ids[j]] < candidates[ids[j-1]]
+			if descending {
+				swap = candidates[ids[j]] > candidates[ids[j-1]]
+			}
+			if !swap {
+				break
+			}
+			ids[j], ids[j-1] = ids[j-1], ids[j]
+		}
+	}
+
+	return ids
+}
+
+// selectLinkAffinityGroup picks the reqCount devices among candidates that
+// maximize the sum of pairwise link weights in matrix. For reqCount up to
+// linkAffinityGreedyThreshold it enumerates every subset exactly; above that
+// it greedily grows a group starting from the highest-weighted edge.
+func selectLinkAffinityGroup(candidates map[int]uint, reqCount int, matrix [][]int) []int {
+	ids := make([]int, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	if reqCount >= len(ids) {
+		return ids
+	}
+
+	weight := func(a, b int) int {
+		if a >= len(matrix) || b >= len(matrix[a]) {
+			return LinkWeightSYS
+		}
+		return matrix[a][b]
+	}
+
+	groupWeight := func(group []int) int {
+		sum := 0
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				sum += weight(group[i], group[j])
+			}
+		}
+		return sum
+	}
+
+	if reqCount <= linkAffinityGreedyThreshold {
+		best := ids[:reqCount]
+		bestWeight := -1
+		var enumerate func(start int, chosen []int)
+		enumerate = func(start int, chosen []int) {
+			if len(chosen) == reqCount {
+				if w := groupWeight(chosen); w > bestWeight {
+					bestWeight = w
+					best = append([]int{}, chosen...)
+				}
+				return
+			}
+			for i := start; i < len(ids); i++ {
+				enumerate(i+1, append(chosen, ids[i]))
+			}
+		}
+		enumerate(0, []int{})
+		return best
+	}
+
+	// Greedy expansion from the highest-weighted seed edge for large k.
+	seedA, seedB, bestEdge := ids[0], ids[1], -1
+	for i := 0; i < len(ids); i++ {
+		for j := i + 1; j < len(ids); j++ {
+			if w := weight(ids[i], ids[j]); w > bestEdge {
+				bestEdge, seedA, seedB = w, ids[i], ids[j]
+			}
+		}
+	}
+
+	group := []int{seedA, seedB}
+	inGroup := map[int]bool{seedA: true, seedB: true}
+	for len(group) < reqCount {
+		bestID, bestScore := -1, -1
+		for _, id := range ids {
+			if inGroup[id] {
+				continue
+			}
+			score := 0
+			for _, member := range group {
+				score += weight(id, member)
+			}
+			if score > bestScore {
+				bestScore, bestID = score, id
+			}
+		}
+		group = append(group, bestID)
+		inGroup[bestID] = true
+	}
+
+	return group
+}
+
+// selectDevicesByPolicy orders/selects devIDs (restricted to candidates,
+// which already meet the per-device memory requirement) for a pod that
+// needs reqCount GPUs under policy. It returns the first reqCount IDs ready
+// to be marked allocated by allocateGPUIDs.
+func selectDevicesByPolicy(candidates map[int]uint, reqCount int, policy utils.SchedulePolicy, nvlinkMatrix [][]int) []int {
+	switch policy {
+	case utils.SchedulePolicySpread:
+		ids := sortByFreeMemory(candidates, true)
+		if len(ids) > reqCount {
+			ids = ids[:reqCount]
+		}
+		return ids
+	case utils.SchedulePolicyLinkAffinity:
+		if nvlinkMatrix == nil {
+			ids := sortByFreeMemory(candidates, false)
+			if len(ids) > reqCount {
+				ids = ids[:reqCount]
+			}
+			return ids
+		}
+		return selectLinkAffinityGroup(candidates, reqCount, nvlinkMatrix)
+	case utils.SchedulePolicyBinpack:
+		fallthrough
+	default:
+		ids := sortByFreeMemory(candidates, false)
+		if len(ids) > reqCount {
+			ids = ids[:reqCount]
+		}
+		return ids
+	}
+}
@@ -0,0 +1,182 @@
+package hotplug
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/cache"
+)
+
+// NodeInfoGetter is the subset of the scheduler cache the hotplug server
+// needs to reach a node's NodeInfo.
+type NodeInfoGetter interface {
+	GetNodeInfo(name string) (*cache.NodeInfo, bool)
+}
+
+// HotplugServer is the gRPC service a node-side agent calls to grow or
+// shrink an already-bound pod's GPU allocation between AttachGPU/AttachAll/
+// DetachGPU calls, on top of the predicate-time Allocate.
+type HotplugServer interface {
+	AttachGPU(context.Context, *AttachGPURequest) (*AttachGPUResponse, error)
+	AttachAll(context.Context, *AttachAllRequest) (*AttachGPUResponse, error)
+	DetachGPU(context.Context, *DetachGPURequest) (*DetachGPUResponse, error)
+}
+
+// Server implements HotplugServer on top of the scheduler's NodeInfo cache.
+type Server struct {
+	getter    NodeInfoGetter
+	clientset *kubernetes.Clientset
+}
+
+// NewServer returns a Server backed by getter for node lookups and
+// clientset for the pod annotation patches AttachGPU/AttachAll perform.
+func NewServer(getter NodeInfoGetter, clientset *kubernetes.Clientset) *Server {
+	return &Server{getter: getter, clientset: clientset}
+}
+
+func (s *Server) nodeInfo(nodeName string) (*cache.NodeInfo, error) {
+	nodeInfo, found := s.getter.GetNodeInfo(nodeName)
+	if !found {
+		return nil, fmt.Errorf("hotplug: no cached NodeInfo for node %s", nodeName)
+	}
+	return nodeInfo, nil
+}
+
+func (s *Server) AttachGPU(ctx context.Context, req *AttachGPURequest) (*AttachGPUResponse, error) {
+	nodeInfo, err := s.nodeInfo(req.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := s.clientset.CoreV1().Pods(req.PodNamespace).Get(req.PodName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := nodeInfo.AttachGPU(s.clientset, pod, uint(req.ExtraMem), int(req.ExtraCount)); err != nil {
+		return nil, err
+	}
+
+	devIDs := int32sFromInts(nodeInfo.AssignedDevIDs(pod))
+	return &AttachGPUResponse{DevIds: devIDs}, nil
+}
+
+func (s *Server) AttachAll(ctx context.Context, req *AttachAllRequest) (*AttachGPUResponse, error) {
+	nodeInfo, err := s.nodeInfo(req.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := s.clientset.CoreV1().Pods(req.PodNamespace).Get(req.PodName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := nodeInfo.AttachAll(s.clientset, pod, uint(req.ExtraMem)); err != nil {
+		return nil, err
+	}
+
+	devIDs := int32sFromInts(nodeInfo.AssignedDevIDs(pod))
+	return &AttachGPUResponse{DevIds: devIDs}, nil
+}
+
+func (s *Server) DetachGPU(ctx context.Context, req *DetachGPURequest) (*DetachGPUResponse, error) {
+	nodeInfo, err := s.nodeInfo(req.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := s.clientset.CoreV1().Pods(req.PodNamespace).Get(req.PodName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	devIDs := make([]int, 0, len(req.DevIds))
+	for _, id := range req.DevIds {
+		devIDs = append(devIDs, int(id))
+	}
+
+	if err := nodeInfo.DetachGPU(s.clientset, pod, devIDs); err != nil {
+		return nil, err
+	}
+	return &DetachGPUResponse{}, nil
+}
+
+func int32sFromInts(ids []int) []int32 {
+	out := make([]int32, len(ids))
+	for i, id := range ids {
+		out[i] = int32(id)
+	}
+	return out
+}
+
+// RegisterHotplugServer registers srv as the Hotplug gRPC service on s.
+// Reproduced by hand from what protoc-gen-go-grpc would generate for
+// hotplug.proto.
+func RegisterHotplugServer(s *grpc.Server, srv HotplugServer) {
+	s.RegisterService(&hotplugServiceDesc, srv)
+}
+
+var hotplugServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hotplug.Hotplug",
+	HandlerType: (*HotplugServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AttachGPU",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(AttachGPURequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(HotplugServer).AttachGPU(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/hotplug.Hotplug/AttachGPU"}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(HotplugServer).AttachGPU(ctx, req.(*AttachGPURequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+		{
+			MethodName: "AttachAll",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(AttachAllRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(HotplugServer).AttachAll(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/hotplug.Hotplug/AttachAll"}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(HotplugServer).AttachAll(ctx, req.(*AttachAllRequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+		{
+			MethodName: "DetachGPU",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(DetachGPURequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(HotplugServer).DetachGPU(ctx, req)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/hotplug.Hotplug/DetachGPU"}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(HotplugServer).DetachGPU(ctx, req.(*DetachGPURequest))
+				}
+				return interceptor(ctx, req, info, handler)
+			},
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "hotplug.proto",
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+
+	"github.com/AliyunContainerService/gpushare-scheduler-extender/pkg/cache"
+)
+
+// NodeInfoGetter is the subset of the scheduler cache the prioritize
+// handler needs: looking up the cached NodeInfo for a candidate node.
+type NodeInfoGetter interface {
+	GetNodeInfo(name string) (*cache.NodeInfo, bool)
+}
+
+// defaultScorePolicy is the score policy used when the caller doesn't
+// pass one explicitly via the "policy" query parameter.
+const defaultScorePolicy = cache.ScorePolicyBinpack
+
+// PrioritizeRoute returns the http.HandlerFunc for the
+// /gpushare-scheduler/prioritize extender endpoint. It implements the
+// Kubernetes scheduler extender HostPriorityList contract: for every node
+// in the ExtenderArgs, it scores the node with NodeInfo.Score and returns
+// a HostPriorityList in the same order.
+func PrioritizeRoute(getter NodeInfoGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy := r.URL.Query().Get("policy")
+		if policy == "" {
+			policy = defaultScorePolicy
+		}
+
+		var args schedulerapi.ExtenderArgs
+		if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The policy config sets nodeCacheCapable: true, so the scheduler
+		// sends node names in args.NodeNames and leaves args.Nodes nil.
+		// Fall back to args.Nodes.Items for callers that don't opt into
+		// node caching.
+		var nodeNames []string
+		if args.NodeNames != nil {
+			nodeNames = *args.NodeNames
+		} else if args.Nodes != nil {
+			for _, node := range args.Nodes.Items {
+				nodeNames = append(nodeNames, node.Name)
+			}
+		}
+
+		result := make(schedulerapi.HostPriorityList, 0, len(nodeNames))
+		for _, name := range nodeNames {
+			score := int64(0)
+			if nodeInfo, found := getter.GetNodeInfo(name); found {
+				score = nodeInfo.Score(args.Pod, policy)
+			} else {
+				log.Printf("warn: prioritize: no cached NodeInfo for node %s, scoring 0", name)
+			}
+			result = append(result, schedulerapi.HostPriority{Host: name, Score: score})
+		}
+
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("warn: prioritize: failed to encode HostPriorityList: %v", err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// SchedulePolicyAnnotation lets a pod opt into a non-default multi-GPU
+// placement policy for allocateGPUIDs.
+const SchedulePolicyAnnotation = "ALIYUN_COM_GPU_SCHEDULE_POLICY"
+
+// SchedulePolicy selects how allocateGPUIDs picks among several fitting
+// devices when a pod asks for more than one GPU.
+type SchedulePolicy string
+
+const (
+	// SchedulePolicyBinpack fills partially-used GPUs first (ascending free
+	// memory), keeping fully-free GPUs available for later large jobs.
+	SchedulePolicyBinpack SchedulePolicy = "binpack"
+	// SchedulePolicySpread prefers the emptiest GPUs first (descending free
+	// memory), minimizing interference between co-located jobs.
+	SchedulePolicySpread SchedulePolicy = "spread"
+	// SchedulePolicyLinkAffinity picks the subset of devices with the
+	// strongest NVLink/PCIe topology affinity for multi-GPU jobs such as
+	// NCCL allreduce.
+	SchedulePolicyLinkAffinity SchedulePolicy = "link-affinity"
+)
+
+// GetSchedulePolicy returns the SchedulePolicy requested by pod via the
+// ALIYUN_COM_GPU_SCHEDULE_POLICY annotation, or defaultPolicy if the pod
+// doesn't set one or sets an unrecognized value.
+func GetSchedulePolicy(pod *v1.Pod, defaultPolicy SchedulePolicy) SchedulePolicy {
+	policy, ok := pod.ObjectMeta.Annotations[SchedulePolicyAnnotation]
+	if !ok {
+		return defaultPolicy
+	}
+
+	switch SchedulePolicy(policy) {
+	case SchedulePolicyBinpack, SchedulePolicySpread, SchedulePolicyLinkAffinity:
+		return SchedulePolicy(policy)
+	default:
+		return defaultPolicy
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// ComputePolicyAnnotation lets a pod opt into memory overcommit for its GPU
+// share instead of the default 1:1 reservation.
+const ComputePolicyAnnotation = "ALIYUN_COM_GPU_COMPUTE_POLICY"
+
+// EffectiveComputePolicyAnnotation records the policy that was actually
+// applied for the pod at Allocate time, so downstream device plugins (e.g.
+// cGPU) can enforce the matching runtime limit.
+const EffectiveComputePolicyAnnotation = "ALIYUN_COM_GPU_COMPUTE_POLICY_EFFECTIVE"
+
+// ComputePolicy selects how strictly a pod's GPU memory share is enforced.
+type ComputePolicy string
+
+const (
+	// ComputePolicyFixedShare is the original behavior: reserved memory is
+	// subtracted 1:1 from a device's availability.
+	ComputePolicyFixedShare ComputePolicy = "fixed-share"
+	// ComputePolicyBurstShare reserves memory as requested but allows the
+	// device's advertised capacity to be overcommitted by
+	// BurstShareOvercommitFactor, as long as no fixed-share pod is also
+	// scheduled on that device.
+	ComputePolicyBurstShare ComputePolicy = "burst-share"
+)
+
+// GetComputePolicy returns the ComputePolicy requested by pod via the
+// ALIYUN_COM_GPU_COMPUTE_POLICY annotation, defaulting to fixed-share (the
+// original, non-overcommitted behavior) when unset or unrecognized.
+func GetComputePolicy(pod *v1.Pod) ComputePolicy {
+	policy, ok := pod.ObjectMeta.Annotations[ComputePolicyAnnotation]
+	if !ok {
+		return ComputePolicyFixedShare
+	}
+
+	switch ComputePolicy(policy) {
+	case ComputePolicyFixedShare, ComputePolicyBurstShare:
+		return ComputePolicy(policy)
+	default:
+		return ComputePolicyFixedShare
+	}
+}
+
+// assignedGPUMemoryAnnotation records the per-device GPU memory, in MiB,
+// that PatchPodAnnotationSpec assigned to a pod at Allocate time.
+const assignedGPUMemoryAnnotation = "ALIYUN_COM_GPU_MEM_ASSIGNED"
+
+// GetGPUMemoryFromPodAnnotation returns the per-device GPU memory, in MiB,
+// that was assigned to pod at Allocate time via its predicate-time
+// annotation, or 0 if the pod hasn't been assigned one.
+func GetGPUMemoryFromPodAnnotation(pod *v1.Pod) (gpuMem int) {
+	if len(pod.ObjectMeta.Annotations) == 0 {
+		return 0
+	}
+
+	value, ok := pod.ObjectMeta.Annotations[assignedGPUMemoryAnnotation]
+	if !ok {
+		return 0
+	}
+
+	mem, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return mem
+}